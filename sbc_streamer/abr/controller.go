@@ -0,0 +1,123 @@
+// Package abr implements an AIMD (additive-increase/multiplicative-
+// decrease) controller that turns RTCP receiver reports into a target
+// video bitrate, so a tele-operated session degrades gracefully
+// instead of dropping when the LoRa-link gets noisy.
+package abr
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Report is the subset of an RTCP receiver report the controller
+// reacts to.
+type Report struct {
+	FractionLost float64 // 0..1
+	JitterMillis float64
+	RTTMillis    float64
+}
+
+// Config bounds and tunes the AIMD loop.
+type Config struct {
+	MinBitrateBps           int
+	MaxBitrateBps           int
+	StepUpBps               int
+	DecreaseFactor          float64 // applied to current bitrate on a degraded report
+	LossThreshold           float64 // fraction lost above which the link is "degraded"
+	JitterThresholdMillis   float64
+	MinInterval             time.Duration // no more than one change per this interval
+	CleanReportsForIncrease int           // consecutive clean reports before stepping up
+}
+
+// DefaultConfig matches the values in the change request: 0.8x
+// multiplicative decrease on >5% loss, +100kbps after 10 clean
+// reports, at most one change every 2 seconds.
+func DefaultConfig(minBps, maxBps int) Config {
+	return Config{
+		MinBitrateBps:           minBps,
+		MaxBitrateBps:           maxBps,
+		StepUpBps:               100_000,
+		DecreaseFactor:          0.8,
+		LossThreshold:           0.05,
+		JitterThresholdMillis:   50,
+		MinInterval:             2 * time.Second,
+		CleanReportsForIncrease: 10,
+	}
+}
+
+// Controller tracks the current target bitrate and decides, on each
+// RTCP report, whether and how to adjust it.
+type Controller struct {
+	mu          sync.Mutex
+	cfg         Config
+	current     int
+	lastChange  time.Time
+	cleanStreak int
+}
+
+// NewController starts the loop at initialBitrateBps.
+func NewController(cfg Config, initialBitrateBps int) *Controller {
+	return &Controller{cfg: cfg, current: initialBitrateBps}
+}
+
+// Current returns the controller's present target bitrate.
+func (c *Controller) Current() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+// Update feeds one RTCP receiver report into the AIMD loop. It
+// returns the new target bitrate, whether it changed, and whether the
+// change is large enough (>15%) that the encoder should be restarted
+// rather than reconfigured in place.
+func (c *Controller) Update(r Report) (newBitrateBps int, changed bool, needsRestart bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	degraded := r.FractionLost > c.cfg.LossThreshold || r.JitterMillis > c.cfg.JitterThresholdMillis
+
+	// Track the clean streak on every report, even ones gated by
+	// MinInterval below -- otherwise an interval longer than the RTCP
+	// report cadence throttles the observation itself, not just the
+	// rate of change, and "N consecutive clean reports" needs far more
+	// than N reports to ever fire.
+	if degraded {
+		c.cleanStreak = 0
+	} else {
+		c.cleanStreak++
+	}
+
+	now := time.Now()
+	if !c.lastChange.IsZero() && now.Sub(c.lastChange) < c.cfg.MinInterval {
+		return c.current, false, false
+	}
+
+	next := c.current
+	if degraded {
+		next = clamp(int(float64(c.current)*c.cfg.DecreaseFactor), c.cfg.MinBitrateBps, c.cfg.MaxBitrateBps)
+	} else if c.cleanStreak >= c.cfg.CleanReportsForIncrease {
+		next = clamp(c.current+c.cfg.StepUpBps, c.cfg.MinBitrateBps, c.cfg.MaxBitrateBps)
+		c.cleanStreak = 0
+	}
+
+	if next == c.current {
+		return c.current, false, false
+	}
+
+	delta := math.Abs(float64(next-c.current)) / float64(c.current)
+	c.current = next
+	c.lastChange = now
+	return next, true, delta > 0.15
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,154 @@
+package abr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestControllerDecreasesOnDegradedReport(t *testing.T) {
+	c := NewController(Config{
+		MinBitrateBps:  100_000,
+		MaxBitrateBps:  4_000_000,
+		StepUpBps:      100_000,
+		DecreaseFactor: 0.8,
+		LossThreshold:  0.05,
+		MinInterval:    0,
+	}, 1_000_000)
+
+	next, changed, _ := c.Update(Report{FractionLost: 0.10})
+	if !changed {
+		t.Fatalf("Update() changed = false, want true for a degraded report")
+	}
+	if want := 800_000; next != want {
+		t.Errorf("Update() newBitrateBps = %d, want %d (0.8x decrease)", next, want)
+	}
+	if got := c.Current(); got != 800_000 {
+		t.Errorf("Current() = %d, want 800000", got)
+	}
+}
+
+func TestControllerIncreasesAfterCleanStreak(t *testing.T) {
+	c := NewController(Config{
+		MinBitrateBps:           100_000,
+		MaxBitrateBps:           4_000_000,
+		StepUpBps:               50_000,
+		DecreaseFactor:          0.8,
+		LossThreshold:           0.05,
+		JitterThresholdMillis:   50,
+		MinInterval:             0,
+		CleanReportsForIncrease: 3,
+	}, 1_000_000)
+
+	for i := 0; i < 2; i++ {
+		_, changed, _ := c.Update(Report{FractionLost: 0})
+		if changed {
+			t.Fatalf("Update() changed = true on clean report %d, want false before the streak completes", i+1)
+		}
+	}
+
+	next, changed, needsRestart := c.Update(Report{FractionLost: 0})
+	if !changed {
+		t.Fatalf("Update() changed = false on the %dth consecutive clean report, want true", 3)
+	}
+	if want := 1_050_000; next != want {
+		t.Errorf("Update() newBitrateBps = %d, want %d (+StepUpBps)", next, want)
+	}
+	if needsRestart {
+		t.Errorf("Update() needsRestart = true for a %.1f%% step, want false", 5.0)
+	}
+}
+
+func TestControllerNeedsRestartAbove15Percent(t *testing.T) {
+	c := NewController(Config{
+		MinBitrateBps:  100_000,
+		MaxBitrateBps:  4_000_000,
+		DecreaseFactor: 0.8, // 20% drop, above the 15% restart boundary
+		LossThreshold:  0.05,
+		MinInterval:    0,
+	}, 1_000_000)
+
+	_, changed, needsRestart := c.Update(Report{FractionLost: 0.10})
+	if !changed {
+		t.Fatalf("Update() changed = false, want true")
+	}
+	if !needsRestart {
+		t.Errorf("Update() needsRestart = false for a 20%% drop, want true (>15%% boundary)")
+	}
+}
+
+func TestControllerRespectsMinInterval(t *testing.T) {
+	c := NewController(Config{
+		MinBitrateBps:  100_000,
+		MaxBitrateBps:  4_000_000,
+		DecreaseFactor: 0.8,
+		LossThreshold:  0.05,
+		MinInterval:    time.Hour,
+	}, 1_000_000)
+
+	_, changed, _ := c.Update(Report{FractionLost: 0.10})
+	if !changed {
+		t.Fatalf("first Update() changed = false, want true")
+	}
+
+	next, changed, _ := c.Update(Report{FractionLost: 0.10})
+	if changed {
+		t.Errorf("second Update() within MinInterval changed = true, want false")
+	}
+	if next != c.Current() {
+		t.Errorf("Update() returned %d while gated, want the unchanged current bitrate %d", next, c.Current())
+	}
+}
+
+func TestControllerCountsCleanStreakWhileGated(t *testing.T) {
+	c := NewController(Config{
+		MinBitrateBps:           100_000,
+		MaxBitrateBps:           4_000_000,
+		StepUpBps:               50_000,
+		DecreaseFactor:          0.8,
+		LossThreshold:           0.05,
+		JitterThresholdMillis:   50,
+		MinInterval:             30 * time.Millisecond,
+		CleanReportsForIncrease: 3,
+	}, 1_000_000)
+
+	// A degraded report applies immediately and starts the MinInterval
+	// gate.
+	if _, changed, _ := c.Update(Report{FractionLost: 0.10}); !changed {
+		t.Fatalf("degraded Update() changed = false, want true")
+	}
+
+	// These three clean reports all land inside the MinInterval window
+	// and are gated -- but the clean streak must still advance on each
+	// one, not just the ones that are allowed to apply.
+	for i := 0; i < 3; i++ {
+		_, changed, _ := c.Update(Report{FractionLost: 0})
+		if changed {
+			t.Fatalf("gated clean Update() #%d changed = true, want false", i+1)
+		}
+	}
+
+	time.Sleep(35 * time.Millisecond)
+
+	// The streak already reached CleanReportsForIncrease while gated,
+	// so the very next report past the gate should apply the increase
+	// immediately rather than needing 3 more fresh observations.
+	next, changed, _ := c.Update(Report{FractionLost: 0})
+	if !changed {
+		t.Fatalf("first post-gate Update() changed = false, want true (streak observed while gated)")
+	}
+	if want := 850_000; next != want {
+		t.Errorf("Update() newBitrateBps = %d, want %d (+StepUpBps on the post-decrease bitrate)", next, want)
+	}
+}
+
+func TestClampBounds(t *testing.T) {
+	if got := clamp(50, 100, 200); got != 100 {
+		t.Errorf("clamp(50, 100, 200) = %d, want 100", got)
+	}
+	if got := clamp(250, 100, 200); got != 200 {
+		t.Errorf("clamp(250, 100, 200) = %d, want 200", got)
+	}
+	if got := clamp(150, 100, 200); got != 150 {
+		t.Errorf("clamp(150, 100, 200) = %d, want 150", got)
+	}
+}
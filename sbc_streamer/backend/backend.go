@@ -0,0 +1,19 @@
+// Package backend abstracts the process that actually turns captured
+// video into an RTSP publish, so main can switch between shelling out
+// to ffmpeg and shelling out to a GStreamer pipeline via the
+// ENCODER_BACKEND env var without duplicating the retry/reconfigure
+// loop.
+package backend
+
+import "context"
+
+// Backend builds and runs one streaming pipeline. Build prepares
+// whatever Run needs (argv, a pipeline string) without starting
+// anything; Run starts the underlying process and blocks until it
+// exits or ctx is cancelled, at which point Run stops the process and
+// returns.
+type Backend interface {
+	Build() error
+	Run(ctx context.Context) error
+	Name() string
+}
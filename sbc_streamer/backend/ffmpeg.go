@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownGrace is how long Run waits for a SIGTERM'd child to exit
+// on its own, e.g. to finish an RTSP TEARDOWN, before it's killed.
+const shutdownGrace = 2 * time.Second
+
+// FFmpegBackend runs the existing ffmpeg-exec pipeline: argv is built
+// by the caller (buildFFmpegArgs in main) since it depends on
+// streamerConfig, which this package doesn't know about.
+type FFmpegBackend struct {
+	Binary string
+	Args   []string
+
+	// ExtraFiles are inherited by the child starting at fd 3, in
+	// order, e.g. the write end of a pipe for a second `-f mpegts
+	// pipe:3` output feeding the clip buffer. Run closes its own
+	// copies once the child has them open.
+	ExtraFiles []*os.File
+
+	// ZmqCommandAddr is the bind address of a `zmq` filter in Args'
+	// `-vf` chain (e.g. "tcp://127.0.0.1:5555"), present when the
+	// caller wants SendKeyframeCommand to be able to reach the running
+	// pipeline. Empty disables keyframe forcing for this backend.
+	ZmqCommandAddr string
+	// ZmqCommandTarget and ZmqCommandText name the filtergraph node
+	// and command SendKeyframeCommand sends, in the "<target>
+	// <command>" form zmqsend expects. Each defaults on its own when
+	// empty: the target to "out", the command to "force_key_frame 1". What that command actually does is up to the
+	// filter the operator named -- ffmpeg ships no standard filter
+	// that forces an encoder keyframe on command, so this only works
+	// end to end if the deployed filtergraph/ffmpeg build backs it
+	// with one.
+	ZmqCommandTarget string
+	ZmqCommandText   string
+	// ZmqsendBinary is the zmqsend CLI ffmpeg ships in tools/zmqsend
+	// for driving a `zmq`/`azmq` filter's REP socket. Defaults to
+	// "zmqsend" on PATH if empty.
+	ZmqsendBinary string
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+// NewFFmpegBackend returns a Backend that execs binary with args.
+func NewFFmpegBackend(binary string, args []string) *FFmpegBackend {
+	return &FFmpegBackend{Binary: binary, Args: args}
+}
+
+func (b *FFmpegBackend) Name() string { return "ffmpeg" }
+
+// Build is a no-op: argv is already fixed at construction time.
+func (b *FFmpegBackend) Build() error { return nil }
+
+// Run execs the pipeline and blocks until it exits. On ctx
+// cancellation it asks ffmpeg to stop gracefully (SIGTERM) rather
+// than relying on exec.CommandContext's default hard kill, so an
+// in-flight RTSP TEARDOWN or output trailer gets a chance to flush.
+func (b *FFmpegBackend) Run(ctx context.Context) error {
+	cmd := exec.Command(b.Binary, b.Args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = b.ExtraFiles
+
+	if err := cmd.Start(); err != nil {
+		for _, f := range b.ExtraFiles {
+			f.Close()
+		}
+		return fmt.Errorf("ffmpeg backend: start: %w", err)
+	}
+	for _, f := range b.ExtraFiles {
+		f.Close()
+	}
+
+	b.mu.Lock()
+	b.cmd = cmd
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.cmd = nil
+		b.mu.Unlock()
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(shutdownGrace):
+			cmd.Process.Kill()
+			return <-done
+		}
+	}
+}
+
+// SendKeyframeCommand asks the running ffmpeg's zmq filter to force an
+// out-of-band keyframe, without restarting the pipeline. It shells
+// out to zmqsend (the REQ client ffmpeg itself ships in tools/zmqsend)
+// rather than speaking the ZMTP wire protocol directly, the same way
+// Run shells out to ffmpeg itself instead of linking against it.
+func (b *FFmpegBackend) SendKeyframeCommand(ctx context.Context) error {
+	if b.ZmqCommandAddr == "" {
+		return fmt.Errorf("ffmpeg backend: no zmq command address configured")
+	}
+
+	target := b.ZmqCommandTarget
+	if target == "" {
+		target = "out"
+	}
+	command := b.ZmqCommandText
+	if command == "" {
+		command = "force_key_frame 1"
+	}
+	binary := b.ZmqsendBinary
+	if binary == "" {
+		binary = "zmqsend"
+	}
+
+	cmd := exec.CommandContext(ctx, binary, "-b", b.ZmqCommandAddr, "-c", target+" "+command)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg backend: send keyframe command: %w: %s", err, out)
+	}
+	return nil
+}
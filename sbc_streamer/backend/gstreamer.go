@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// GstConfig is the subset of streamerConfig a GStreamer pipeline needs.
+type GstConfig struct {
+	Binary        string // gst-launch-1.0 by default
+	CameraDevice  string
+	Width         int
+	Height        int
+	FrameRate     int
+	BitrateKbps   int
+	GOPSize       int
+	StreamName    string
+	TargetHost    string
+	PublishUser   string
+	PublishPass   string
+	// RTSPTransport is passed straight through as rtspclientsink's
+	// protocols= property. It is NOT translated from ffmpeg's
+	// -rtsp_transport vocabulary (e.g. "udp_multicast" vs gst's
+	// "udp-mcast") — only "tcp" and "udp" are spelled the same in
+	// both, so anything else needs a value specific to this backend.
+	RTSPTransport string
+}
+
+// GStreamerBackend runs a `gst-launch-1.0` pipeline built around the
+// Rockchip `mpph264enc` element, which (unlike ffmpeg's h264_rkmpp)
+// supports live bitrate/GOP property updates — a future iteration can
+// push those through gst_element_set_property via go-gst instead of
+// restarting the pipeline, once that binding is vendored for this
+// target.
+type GStreamerBackend struct {
+	cfg      GstConfig
+	pipeline string
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+// NewGStreamerBackend returns a Backend driven by gst-launch-1.0.
+func NewGStreamerBackend(cfg GstConfig) *GStreamerBackend {
+	if cfg.Binary == "" {
+		cfg.Binary = "gst-launch-1.0"
+	}
+	return &GStreamerBackend{cfg: cfg}
+}
+
+func (b *GStreamerBackend) Name() string { return "gstreamer" }
+
+func (b *GStreamerBackend) Build() error {
+	b.pipeline = buildPipeline(b.cfg)
+	return nil
+}
+
+// Run execs the pipeline and blocks until it exits. On ctx
+// cancellation it asks gst-launch-1.0 to stop gracefully (SIGTERM,
+// which gst-launch handles as EOS-then-exit) rather than relying on
+// exec.CommandContext's default hard kill, giving rtspclientsink a
+// chance to send TEARDOWN.
+func (b *GStreamerBackend) Run(ctx context.Context) error {
+	if b.pipeline == "" {
+		if err := b.Build(); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command(b.cfg.Binary, "-e", "-v", b.pipeline)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("gstreamer backend: start: %w", err)
+	}
+
+	b.mu.Lock()
+	b.cmd = cmd
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.cmd = nil
+		b.mu.Unlock()
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(shutdownGrace):
+			cmd.Process.Kill()
+			return <-done
+		}
+	}
+}
+
+func buildPipeline(cfg GstConfig) string {
+	var auth string
+	if cfg.PublishUser != "" {
+		auth = cfg.PublishUser
+		if cfg.PublishPass != "" {
+			auth += ":" + cfg.PublishPass
+		}
+		auth += "@"
+	}
+	url := fmt.Sprintf("rtsp://%s%s/%s", auth, cfg.TargetHost, cfg.StreamName)
+
+	elements := []string{
+		fmt.Sprintf("v4l2src device=%s", cfg.CameraDevice),
+		fmt.Sprintf("video/x-raw,width=%d,height=%d,framerate=%d/1", cfg.Width, cfg.Height, cfg.FrameRate),
+		"videoconvert",
+		fmt.Sprintf("mpph264enc bitrate=%d gop-size=%d", cfg.BitrateKbps*1000, cfg.GOPSize),
+		"h264parse",
+		fmt.Sprintf("rtspclientsink location=%s protocols=%s", url, cfg.RTSPTransport),
+	}
+	return strings.Join(elements, " ! ")
+}
@@ -0,0 +1,191 @@
+// Package broadcast runs secondary output pipelines — RTMP fan-out,
+// rolling file recording — alongside the primary RTSP publish, each
+// independently startable/stoppable without touching the main encoder.
+//
+// Rather than tee-ing the primary ffmpeg's raw output, each sink
+// re-pulls the stream we just published from the relay with
+// `-c copy`, so no re-encoding happens and a sink crashing or
+// restarting never perturbs the primary pipeline.
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SinkStatus reports a secondary output's current state.
+type SinkStatus struct {
+	URL      string
+	Running  bool
+	Restarts int
+	LastErr  string
+}
+
+type sink struct {
+	url      string
+	cancel   context.CancelFunc
+	restarts int
+	lastErr  string
+	running  bool
+}
+
+// Manager owns zero or more secondary output pipelines that all read
+// from the same source RTSP URL.
+type Manager struct {
+	mu           sync.Mutex
+	ffmpegBinary string
+	sourceURL    string
+	logger       *log.Logger
+	ctx          context.Context
+	retryDelay   time.Duration
+	sinks        map[string]*sink
+}
+
+// NewManager returns a Manager bound to ctx: every sink it starts is
+// torn down automatically when ctx is cancelled (process shutdown).
+func NewManager(ctx context.Context, ffmpegBinary, sourceURL string, logger *log.Logger) *Manager {
+	return &Manager{
+		ffmpegBinary: ffmpegBinary,
+		sourceURL:    sourceURL,
+		logger:       logger,
+		ctx:          ctx,
+		retryDelay:   3 * time.Second,
+		sinks:        make(map[string]*sink),
+	}
+}
+
+// Start launches a secondary output for url if it is not already
+// running. It returns once the sink goroutine has been spawned, not
+// once the sink is connected.
+func (m *Manager) Start(url string) error {
+	args, err := buildSinkArgs(m.sourceURL, url)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if existing, ok := m.sinks[url]; ok && existing.running {
+		m.mu.Unlock()
+		return nil
+	}
+	sinkCtx, cancel := context.WithCancel(m.ctx)
+	s := &sink{url: url, cancel: cancel, running: true}
+	m.sinks[url] = s
+	m.mu.Unlock()
+
+	go m.run(sinkCtx, s, args)
+	return nil
+}
+
+// Stop cancels the secondary output for url, if running. It is not an
+// error to Stop a url that was never started.
+func (m *Manager) Stop(url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sinks[url]
+	if !ok {
+		return nil
+	}
+	s.cancel()
+	s.running = false
+	return nil
+}
+
+// List returns the configured sink URLs.
+func (m *Manager) List() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	urls := make([]string, 0, len(m.sinks))
+	for url := range m.sinks {
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// Status returns a snapshot of every known sink.
+func (m *Manager) Status() []SinkStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]SinkStatus, 0, len(m.sinks))
+	for _, s := range m.sinks {
+		out = append(out, SinkStatus{
+			URL:      s.url,
+			Running:  s.running,
+			Restarts: s.restarts,
+			LastErr:  s.lastErr,
+		})
+	}
+	return out
+}
+
+// run restarts the sink's ffmpeg child with the same 3s backoff the
+// primary pipeline uses, until sinkCtx is cancelled by Stop or process
+// shutdown.
+func (m *Manager) run(sinkCtx context.Context, s *sink, args []string) {
+	for {
+		cmd := exec.CommandContext(sinkCtx, m.ffmpegBinary, args...)
+		m.logger.Printf("broadcast: starting sink %s", s.url)
+		err := cmd.Run()
+
+		m.mu.Lock()
+		s.lastErr = errString(err)
+		m.mu.Unlock()
+
+		if sinkCtx.Err() != nil {
+			m.mu.Lock()
+			s.running = false
+			m.mu.Unlock()
+			return
+		}
+
+		m.logger.Printf("broadcast: sink %s exited: %v, retrying in %s", s.url, err, m.retryDelay)
+		m.mu.Lock()
+		s.restarts++
+		m.mu.Unlock()
+
+		select {
+		case <-time.After(m.retryDelay):
+		case <-sinkCtx.Done():
+			m.mu.Lock()
+			s.running = false
+			m.mu.Unlock()
+			return
+		}
+	}
+}
+
+// buildSinkArgs translates a BROADCAST_URLS entry into ffmpeg
+// arguments that copy the already-encoded stream to that destination.
+// Supported schemes: rtmp:// (flv fan-out) and file:// (rolling,
+// strftime-named segments).
+func buildSinkArgs(sourceURL, url string) ([]string, error) {
+	args := []string{"-i", sourceURL, "-c", "copy"}
+
+	switch {
+	case strings.HasPrefix(url, "rtmp://"):
+		return append(args, "-f", "flv", url), nil
+	case strings.HasPrefix(url, "file://"):
+		path := strings.TrimPrefix(url, "file://")
+		return append(args,
+			"-f", "segment",
+			"-strftime", "1",
+			"-segment_time", "3600",
+			"-reset_timestamps", "1",
+			path,
+		), nil
+	default:
+		return nil, fmt.Errorf("broadcast: unsupported sink url %q", url)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
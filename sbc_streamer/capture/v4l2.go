@@ -0,0 +1,98 @@
+//go:build native
+// +build native
+
+// Package capture wraps V4L2 MMAP capture for the native streamer
+// variant, so video frames reach the encoder without an ffmpeg
+// subprocess in between.
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vladimirvivien/go4vl/device"
+	"github.com/vladimirvivien/go4vl/v4l2"
+)
+
+// v4l2Fourcc reproduces the kernel's v4l2_fourcc() macro for pixel
+// formats go4vl doesn't export a constant for (NV12, YUV420 planar).
+func v4l2Fourcc(a, b, c, d byte) v4l2.FourCCType {
+	return v4l2.FourCCType(a) | v4l2.FourCCType(b)<<8 | v4l2.FourCCType(c)<<16 | v4l2.FourCCType(d)<<24
+}
+
+var (
+	pixFmtNV12    = v4l2Fourcc('N', 'V', '1', '2')
+	pixFmtYUV420P = v4l2Fourcc('Y', 'U', '1', '2')
+)
+
+// Device streams raw frames from a V4L2 capture device using MMAP
+// buffers.
+type Device struct {
+	dev *device.Device
+}
+
+// Open configures pixel format, resolution and buffer count; it does
+// not start streaming, which is Start's job. Frames returned by Frames() are owned by the
+// kernel's MMAP ring until the next call, matching go4vl's buffer
+// lifetime.
+func Open(path string, width, height int, pixFmt string) (*Device, error) {
+	fourcc, err := parsePixFmt(pixFmt)
+	if err != nil {
+		return nil, fmt.Errorf("capture: %w", err)
+	}
+
+	dev, err := device.Open(path,
+		device.WithPixFormat(v4l2.PixFormat{
+			Width:       uint32(width),
+			Height:      uint32(height),
+			PixelFormat: fourcc,
+			Field:       v4l2.FieldNone,
+		}),
+		device.WithBufferSize(4),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("capture: open %s: %w", path, err)
+	}
+
+	return &Device{dev: dev}, nil
+}
+
+// Start begins streaming; Frames() is only valid to read after Start
+// returns without error.
+func (d *Device) Start(ctx context.Context) error {
+	if err := d.dev.Start(ctx); err != nil {
+		return fmt.Errorf("capture: start streaming: %w", err)
+	}
+	return nil
+}
+
+// Frames returns the channel of captured frame buffers.
+func (d *Device) Frames() <-chan []byte {
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		for frame := range d.dev.GetOutput() {
+			out <- frame
+		}
+	}()
+	return out
+}
+
+// Close stops streaming and releases the device file descriptor.
+func (d *Device) Close() error {
+	d.dev.Close()
+	return nil
+}
+
+func parsePixFmt(name string) (v4l2.FourCCType, error) {
+	switch name {
+	case "", "nv12":
+		return pixFmtNV12, nil
+	case "yuyv":
+		return v4l2.PixelFmtYUYV, nil
+	case "yuv420", "yu12":
+		return pixFmtYUV420P, nil
+	default:
+		return 0, fmt.Errorf("unsupported pixel format %q", name)
+	}
+}
@@ -0,0 +1,44 @@
+package clip
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// ExportMP4 remuxes packets (already trimmed to start at a keyframe by
+// Queue.Slice) into a fragmented MP4 by piping their raw H.264
+// Annex-B payloads through a short-lived ffmpeg process. Writing MP4
+// boxes by hand is a solved problem ffmpeg already has open on the
+// system for the main pipeline, so this reuses it instead of
+// vendoring a muxer.
+func ExportMP4(ctx context.Context, ffmpegBinary string, packets []Packet) ([]byte, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("clip: no packets in requested range")
+	}
+
+	var h264 bytes.Buffer
+	for _, pkt := range packets {
+		h264.Write(pkt.Payload)
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegBinary,
+		"-f", "h264",
+		"-i", "pipe:0",
+		"-c", "copy",
+		"-movflags", "frag_keyframe+empty_moov",
+		"-f", "mp4",
+		"pipe:1",
+	)
+	cmd.Stdin = &h264
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("clip: remux: %w: %s", err, stderr.String())
+	}
+	return out.Bytes(), nil
+}
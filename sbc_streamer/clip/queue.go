@@ -0,0 +1,87 @@
+// Package clip keeps a short rolling buffer of recently encoded video
+// packets in memory, so the control API can export an "instant
+// replay" clip of the last N seconds on demand without running a
+// permanent recording pipeline.
+package clip
+
+import (
+	"sync"
+	"time"
+)
+
+// Packet is one encoded access unit captured off the clip buffer's TS
+// feed, tagged with the wall-clock time it arrived so Slice can
+// answer "the last N seconds" queries without decoding PTS/DTS
+// against a pipeline start time that may have changed across
+// restarts.
+type Packet struct {
+	Codec    string
+	PTS      time.Duration
+	DTS      time.Duration
+	Keyframe bool
+	Payload  []byte
+	Received time.Time
+}
+
+// Queue is a ring buffer of recently captured Packets, retaining only
+// the last `retention` worth of wall-clock time. It is safe for
+// concurrent use: Push is called from the TS demux goroutine while
+// Slice is called from the control API's clip-export handler.
+type Queue struct {
+	mu        sync.Mutex
+	retention time.Duration
+	packets   []Packet
+}
+
+// NewQueue returns a Queue that retains the last retention worth of
+// packets, evicting older ones on every Push.
+func NewQueue(retention time.Duration) *Queue {
+	return &Queue{retention: retention}
+}
+
+// Push appends pkt and evicts anything older than the retention
+// window relative to pkt.Received.
+func (q *Queue) Push(pkt Packet) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.packets = append(q.packets, pkt)
+
+	cutoff := pkt.Received.Add(-q.retention)
+	evict := 0
+	for evict < len(q.packets) && q.packets[evict].Received.Before(cutoff) {
+		evict++
+	}
+	if evict > 0 {
+		q.packets = append([]Packet(nil), q.packets[evict:]...)
+	}
+}
+
+// Slice returns a copy of the packets covering [from, to], starting
+// at the earliest keyframe received at or after from so the result is
+// independently decodable even though packets before it were already
+// evicted or are simply outside the requested window.
+func (q *Queue) Slice(from, to time.Time) []Packet {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	start := -1
+	for i, pkt := range q.packets {
+		if pkt.Keyframe && !pkt.Received.Before(from) {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil
+	}
+
+	var out []Packet
+	for _, pkt := range q.packets[start:] {
+		if pkt.Received.After(to) {
+			break
+		}
+		out = append(out, pkt)
+	}
+	return out
+}
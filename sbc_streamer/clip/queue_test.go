@@ -0,0 +1,79 @@
+package clip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueuePushEvictsOlderThanRetention(t *testing.T) {
+	q := NewQueue(2 * time.Second)
+	base := time.Now()
+
+	q.Push(Packet{Payload: []byte("a"), Received: base})
+	q.Push(Packet{Payload: []byte("b"), Received: base.Add(1 * time.Second)})
+	q.Push(Packet{Payload: []byte("c"), Received: base.Add(3 * time.Second)})
+
+	got := q.Slice(base.Add(-time.Hour), base.Add(time.Hour))
+	if len(got) != 0 {
+		t.Fatalf("Slice() without a keyframe returned %d packets, want 0", len(got))
+	}
+
+	// "a" was already evicted by the push of "c". Push well past the
+	// retention window and confirm "b" and "c" are evicted too, leaving
+	// only the new packet.
+	q.Push(Packet{Payload: []byte("d"), Received: base.Add(10 * time.Second), Keyframe: true})
+	if n := len(q.packets); n != 1 {
+		t.Fatalf("after eviction len(packets) = %d, want 1 (only the keyframe within retention)", n)
+	}
+	if string(q.packets[0].Payload) != "d" {
+		t.Errorf("surviving packet = %q, want %q", q.packets[0].Payload, "d")
+	}
+}
+
+func TestQueueSliceStartsAtKeyframe(t *testing.T) {
+	q := NewQueue(time.Minute)
+	base := time.Now()
+
+	q.Push(Packet{Payload: []byte("p-frame-before-kf"), Received: base, Keyframe: false})
+	q.Push(Packet{Payload: []byte("keyframe"), Received: base.Add(1 * time.Second), Keyframe: true})
+	q.Push(Packet{Payload: []byte("p-frame-1"), Received: base.Add(2 * time.Second)})
+	q.Push(Packet{Payload: []byte("p-frame-2"), Received: base.Add(3 * time.Second)})
+
+	got := q.Slice(base.Add(500*time.Millisecond), base.Add(time.Hour))
+	if len(got) != 3 {
+		t.Fatalf("Slice() returned %d packets, want 3 (keyframe onward)", len(got))
+	}
+	if string(got[0].Payload) != "keyframe" {
+		t.Errorf("Slice()[0] = %q, want the keyframe, not the leading P-frame", got[0].Payload)
+	}
+}
+
+func TestQueueSliceRespectsToBound(t *testing.T) {
+	q := NewQueue(time.Minute)
+	base := time.Now()
+
+	q.Push(Packet{Payload: []byte("keyframe"), Received: base, Keyframe: true})
+	q.Push(Packet{Payload: []byte("in-range"), Received: base.Add(1 * time.Second)})
+	q.Push(Packet{Payload: []byte("out-of-range"), Received: base.Add(10 * time.Second)})
+
+	got := q.Slice(base, base.Add(5*time.Second))
+	if len(got) != 2 {
+		t.Fatalf("Slice() returned %d packets, want 2 (stopping before the out-of-range packet)", len(got))
+	}
+	for _, pkt := range got {
+		if string(pkt.Payload) == "out-of-range" {
+			t.Errorf("Slice() included a packet received after `to`: %q", pkt.Payload)
+		}
+	}
+}
+
+func TestQueueSliceNoKeyframeReturnsNil(t *testing.T) {
+	q := NewQueue(time.Minute)
+	base := time.Now()
+	q.Push(Packet{Payload: []byte("p-frame"), Received: base, Keyframe: false})
+
+	got := q.Slice(base.Add(-time.Second), base.Add(time.Second))
+	if got != nil {
+		t.Errorf("Slice() with no keyframe in range = %v, want nil", got)
+	}
+}
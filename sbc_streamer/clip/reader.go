@@ -0,0 +1,78 @@
+package clip
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/asticode/go-astits"
+)
+
+// h264ClockRate is the RTP/MPEG-TS clock rate for H.264 PTS/DTS.
+const h264ClockRate = 90_000
+
+// ReadTS demuxes an MPEG-TS stream from r (ffmpeg's second `-f mpegts
+// pipe:3` output) into Packets and pushes each into q. It returns nil
+// once r reaches EOF or ctx is cancelled, and an error for anything
+// else the demuxer reports.
+func ReadTS(ctx context.Context, r io.Reader, q *Queue) error {
+	dmx := astits.NewDemuxer(ctx, r)
+	for {
+		data, err := dmx.NextData()
+		if err != nil {
+			if errors.Is(err, astits.ErrNoMorePackets) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if data.PES == nil {
+			continue
+		}
+
+		// Copy the payload: the demuxer may reuse its internal buffer
+		// on the next NextData() call, and packets here are retained
+		// far longer than one iteration.
+		payload := make([]byte, len(data.PES.Data))
+		copy(payload, data.PES.Data)
+
+		pkt := Packet{
+			Codec:    "h264",
+			Payload:  payload,
+			Keyframe: isH264Keyframe(payload),
+			Received: time.Now(),
+		}
+		if oh := data.PES.Header.OptionalHeader; oh != nil {
+			if oh.PTS != nil {
+				pkt.PTS = time.Duration(oh.PTS.Base) * time.Second / h264ClockRate
+			}
+			if oh.DTS != nil {
+				pkt.DTS = time.Duration(oh.DTS.Base) * time.Second / h264ClockRate
+			}
+		}
+		q.Push(pkt)
+	}
+}
+
+// isH264Keyframe reports whether payload (one PES packet's Annex-B
+// byte stream) contains an IDR slice NAL unit.
+func isH264Keyframe(payload []byte) bool {
+	for i := 0; i+3 < len(payload); i++ {
+		if payload[i] != 0 || payload[i+1] != 0 {
+			continue
+		}
+		var nalStart int
+		switch {
+		case payload[i+2] == 1:
+			nalStart = i + 3
+		case i+4 < len(payload) && payload[i+2] == 0 && payload[i+3] == 1:
+			nalStart = i + 4
+		default:
+			continue
+		}
+		if nalStart < len(payload) && payload[nalStart]&0x1f == 5 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+// Package control exposes an HTTP API for runtime reconfiguration of
+// the streamer pipeline, so an operator can drop bitrate, toggle a
+// broadcast sink, or pull an instant-replay clip when the LoRa-link
+// degrades without losing the video session. It holds no pipeline
+// state itself — the caller wires status/config/restart/clip/broadcast
+// behavior in via callbacks, keeping this package independent of
+// streamerConfig.
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ConfigPatch is the set of streamerConfig fields that can be changed
+// at runtime via POST /config. Fields left nil are unchanged.
+type ConfigPatch struct {
+	VideoBitrate  *string `json:"videoBitrate,omitempty"`
+	VideoMaxRate  *string `json:"videoMaxRate,omitempty"`
+	FrameRate     *string `json:"frameRate,omitempty"`
+	VideoRotation *int    `json:"videoRotation,omitempty"`
+	GopSize       *int    `json:"gopSize,omitempty"`
+	RefreshMode   *string `json:"refreshMode,omitempty"`
+}
+
+// BroadcastSinkStatus reports one secondary output's current state,
+// mirroring broadcast.SinkStatus without importing that package.
+type BroadcastSinkStatus struct {
+	URL      string `json:"url"`
+	Running  bool   `json:"running"`
+	Restarts int    `json:"restarts"`
+	LastErr  string `json:"lastError,omitempty"`
+}
+
+// BroadcastRequest is the JSON body for POST /broadcast/start and
+// POST /broadcast/stop.
+type BroadcastRequest struct {
+	URL string `json:"url"`
+}
+
+// Server is the control-API HTTP server.
+type Server struct {
+	logger *log.Logger
+
+	status         func() any
+	applyPatch     func(ConfigPatch) error
+	restart        func()
+	clip           func(ctx context.Context, seconds int) ([]byte, error)
+	keyframe       func(ctx context.Context) error
+	broadcastStart func(url string) error
+	broadcastStop  func(url string) error
+	broadcastList  func() []BroadcastSinkStatus
+
+	httpServer *http.Server
+}
+
+// NewServer builds a Server. status returns whatever should be
+// serialized for GET /status (config snapshot, broadcast sink state,
+// and so on); applyPatch merges a ConfigPatch into the live config
+// and should trigger a pipeline reconfigure; restart forces a
+// relaunch with the current config; clip exports the last `seconds`
+// of buffered video as an MP4 for POST /clip. clip may be nil if the
+// caller has no clip buffer wired up (e.g. the gstreamer backend),
+// in which case POST /clip responds 501. keyframe asks the live
+// pipeline to force an out-of-band keyframe for POST /keyframe,
+// without a restart; it may be nil if the active backend has no way
+// to do that (e.g. gstreamer, or ffmpeg without a keyframe command
+// channel configured), in which case POST /keyframe responds 501.
+// broadcastStart/broadcastStop toggle a secondary output sink for
+// POST /broadcast/start and POST /broadcast/stop; broadcastList
+// reports current sink state for GET /broadcast.
+func NewServer(logger *log.Logger, status func() any, applyPatch func(ConfigPatch) error, restart func(), clip func(ctx context.Context, seconds int) ([]byte, error), keyframe func(ctx context.Context) error, broadcastStart func(url string) error, broadcastStop func(url string) error, broadcastList func() []BroadcastSinkStatus) *Server {
+	return &Server{
+		logger:         logger,
+		status:         status,
+		applyPatch:     applyPatch,
+		restart:        restart,
+		clip:           clip,
+		keyframe:       keyframe,
+		broadcastStart: broadcastStart,
+		broadcastStop:  broadcastStop,
+		broadcastList:  broadcastList,
+	}
+}
+
+// withMethod rejects requests that don't use method before calling h.
+// The net/http.ServeMux in this repo's pinned go1.21 toolchain doesn't
+// support Go 1.22's "METHOD /path" pattern syntax -- registering one
+// directly would silently register the literal string as a path and
+// never match a real request.
+func withMethod(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// Start listens on addr until ctx is cancelled, at which point it
+// shuts the HTTP server down gracefully.
+func (s *Server) Start(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", withMethod(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/config", withMethod(http.MethodPost, s.handleConfig))
+	mux.HandleFunc("/restart", withMethod(http.MethodPost, s.handleRestart))
+	mux.HandleFunc("/clip", withMethod(http.MethodPost, s.handleClip))
+	mux.HandleFunc("/keyframe", withMethod(http.MethodPost, s.handleKeyframe))
+	mux.HandleFunc("/broadcast", withMethod(http.MethodGet, s.handleBroadcastList))
+	mux.HandleFunc("/broadcast/start", withMethod(http.MethodPost, s.handleBroadcastStart))
+	mux.HandleFunc("/broadcast/stop", withMethod(http.MethodPost, s.handleBroadcastStop))
+
+	s.httpServer = &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+			s.logger.Printf("control: shutdown error: %v", err)
+		}
+	}()
+
+	s.logger.Printf("control: listening on %s", addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("control: listen and serve: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	var patch ConfigPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, fmt.Sprintf("invalid config patch: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := s.applyPatch(patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "applied"})
+}
+
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
+	s.restart()
+	writeJSON(w, http.StatusOK, map[string]string{"result": "restarting"})
+}
+
+func (s *Server) handleClip(w http.ResponseWriter, r *http.Request) {
+	if s.clip == nil {
+		http.Error(w, "clip buffer not available on this backend", http.StatusNotImplemented)
+		return
+	}
+
+	seconds := 30
+	if raw := r.URL.Query().Get("seconds"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("invalid seconds %q", raw), http.StatusBadRequest)
+			return
+		}
+		seconds = n
+	}
+
+	mp4, err := s.clip(r.Context(), seconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=clip-%ds.mp4", seconds))
+	w.Write(mp4)
+}
+
+func (s *Server) handleKeyframe(w http.ResponseWriter, r *http.Request) {
+	if s.keyframe == nil {
+		http.Error(w, "keyframe forcing not available on this backend", http.StatusNotImplemented)
+		return
+	}
+	if err := s.keyframe(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "requested"})
+}
+
+func (s *Server) handleBroadcastList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.broadcastList())
+}
+
+func (s *Server) handleBroadcastStart(w http.ResponseWriter, r *http.Request) {
+	var req BroadcastRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid broadcast request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.broadcastStart(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "started", "url": req.URL})
+}
+
+func (s *Server) handleBroadcastStop(w http.ResponseWriter, r *http.Request) {
+	var req BroadcastRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid broadcast request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.broadcastStop(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "stopped", "url": req.URL})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,43 @@
+//go:build native
+// +build native
+
+// Package encoder provides in-process H.264 encoding for the native
+// streamer variant, so it does not need to shell out to ffmpeg for
+// either capture or compression.
+package encoder
+
+// Packet is one encoded access unit produced by an Encoder.
+type Packet struct {
+	Data     []byte
+	PTS      int64
+	Keyframe bool
+}
+
+// Encoder compresses raw V4L2 frames into H.264 access units.
+type Encoder interface {
+	Encode(frame []byte, pts int64) ([]Packet, error)
+	Close() error
+}
+
+// Config mirrors the handful of streamerConfig fields an Encoder needs
+// to size and rate-control its output.
+type Config struct {
+	Width     int
+	Height    int
+	PixelFmt  string
+	Bitrate   int // bits per second
+	GOPSize   int
+	FrameRate int
+}
+
+// New picks an Encoder implementation: it tries the Rockchip MPP
+// hardware encoder first and falls back to a CGo-wrapped libx264
+// encoder when that fails. newMPPEncoder always fails (its rate-control
+// setup is not implemented), so New returns the libx264 encoder even on
+// boards with MPP present.
+func New(cfg Config) (Encoder, error) {
+	if enc, err := newMPPEncoder(cfg); err == nil {
+		return enc, nil
+	}
+	return newX264Encoder(cfg)
+}
@@ -0,0 +1,63 @@
+//go:build native
+// +build native
+
+package encoder
+
+/*
+#cgo LDFLAGS: -lrockchip_mpp
+#include <rockchip/rk_mpi.h>
+*/
+import "C"
+
+import (
+	"fmt"
+)
+
+// mppEncoder drives the Rockchip MPP hardware H.264 encoder directly,
+// giving the native streamer the same silicon ffmpeg's h264_rkmpp uses
+// but without a subprocess in between.
+type mppEncoder struct {
+	ctx C.MppCtx
+	api *C.MppApi
+	cfg Config
+}
+
+func newMPPEncoder(cfg Config) (Encoder, error) {
+	e := &mppEncoder{cfg: cfg}
+
+	if ret := C.mpp_create(&e.ctx, &e.api); ret != C.MPP_OK {
+		return nil, fmt.Errorf("mpp encoder: mpp_create failed: %d", int(ret))
+	}
+	if ret := C.mpp_init(e.ctx, C.MPP_CTX_ENC, C.MPP_VIDEO_CodingAVC); ret != C.MPP_OK {
+		C.mpp_destroy(e.ctx)
+		return nil, fmt.Errorf("mpp encoder: mpp_init failed: %d", int(ret))
+	}
+
+	// Geometry, rate control, and GOP belong here via
+	// mpi->control(ctx, MPP_ENC_SET_CFG, ...), sized from cfg so the
+	// native path honors the same env-driven knobs the ffmpeg path
+	// exposes (VIDEO_SIZE, VIDEO_BITRATE, GOP_SIZE). Not wired up yet,
+	// so fail construction now rather than fail every Encode call
+	// later; New() falls back to the libx264 encoder below.
+	C.mpp_destroy(e.ctx)
+	return nil, fmt.Errorf("mpp encoder: rate-control setup not implemented yet")
+}
+
+func (e *mppEncoder) Encode(frame []byte, pts int64) ([]Packet, error) {
+	if len(frame) == 0 {
+		return nil, fmt.Errorf("mpp encoder: empty frame")
+	}
+
+	// A full implementation wraps `frame` in an MppBuffer, submits it
+	// via mpi->encode_put_frame, and drains mpi->encode_get_packet in
+	// a loop until MPP_ERR_TIMEOUT.
+	return nil, fmt.Errorf("mpp encoder: hardware path not wired up on this build")
+}
+
+func (e *mppEncoder) Close() error {
+	if e.ctx != nil {
+		C.mpp_destroy(e.ctx)
+		e.ctx = nil
+	}
+	return nil
+}
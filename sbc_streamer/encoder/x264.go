@@ -0,0 +1,133 @@
+//go:build native
+// +build native
+
+package encoder
+
+/*
+#cgo pkg-config: x264
+#include <stdint.h>
+#include <string.h>
+#include <x264.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// x264Encoder is the software fallback used when the Rockchip MPP
+// hardware path is unavailable or not yet configured for this board.
+type x264Encoder struct {
+	enc *C.x264_t
+	pic C.x264_picture_t
+	cfg Config
+	csp C.int // X264_CSP_NV12 or X264_CSP_I420, matching cfg.PixelFmt
+}
+
+// cspForPixelFmt maps the capture pixel format (see capture.parsePixFmt)
+// to the matching x264 colorspace constant. Feeding x264 an I420
+// picture with NV12's semi-planar (interleaved UV) data corrupts
+// chroma, so the two must always be kept in sync.
+func cspForPixelFmt(pixFmt string) C.int {
+	switch pixFmt {
+	case "yuv420", "yu12":
+		return C.X264_CSP_I420
+	default: // "", "nv12"
+		return C.X264_CSP_NV12
+	}
+}
+
+func newX264Encoder(cfg Config) (Encoder, error) {
+	var params C.x264_param_t
+	C.x264_param_default_preset(&params, C.CString("ultrafast"), C.CString("zerolatency"))
+
+	params.i_width = C.int(cfg.Width)
+	params.i_height = C.int(cfg.Height)
+	params.i_fps_num = C.uint32_t(cfg.FrameRate)
+	params.i_fps_den = 1
+	params.i_keyint_max = C.int(cfg.GOPSize)
+	params.rc.i_bitrate = C.int(cfg.Bitrate / 1000) // kbps
+	params.rc.i_rc_method = C.X264_RC_ABR
+	params.b_repeat_headers = 1
+	params.b_annexb = 1
+
+	if cfg.PixelFmt == "yuyv" {
+		return nil, fmt.Errorf("x264 encoder: pixel format %q has no matching colorspace here", cfg.PixelFmt)
+	}
+	csp := cspForPixelFmt(cfg.PixelFmt)
+	params.i_csp = csp
+
+	if ret := C.x264_param_apply_profile(&params, C.CString("baseline")); ret < 0 {
+		return nil, fmt.Errorf("x264 encoder: apply profile: %d", int(ret))
+	}
+
+	e := &x264Encoder{cfg: cfg, csp: csp}
+	e.enc = C.x264_encoder_open(&params)
+	if e.enc == nil {
+		return nil, fmt.Errorf("x264 encoder: x264_encoder_open failed")
+	}
+
+	if C.x264_picture_alloc(&e.pic, csp, C.int(cfg.Width), C.int(cfg.Height)) < 0 {
+		C.x264_encoder_close(e.enc)
+		return nil, fmt.Errorf("x264 encoder: picture_alloc failed")
+	}
+
+	return e, nil
+}
+
+func (e *x264Encoder) Encode(frame []byte, pts int64) ([]Packet, error) {
+	if len(frame) == 0 {
+		return nil, fmt.Errorf("x264 encoder: empty frame")
+	}
+
+	planeSize := int(e.cfg.Width * e.cfg.Height)
+	if e.csp == C.X264_CSP_NV12 {
+		// Semi-planar: one interleaved UV plane, half the luma size.
+		chromaSize := planeSize / 2
+		if len(frame) < planeSize+chromaSize {
+			return nil, fmt.Errorf("x264 encoder: frame too small for NV12 %dx%d", e.cfg.Width, e.cfg.Height)
+		}
+		C.memcpy(unsafe.Pointer(e.pic.img.plane[0]), unsafe.Pointer(&frame[0]), C.size_t(planeSize))
+		C.memcpy(unsafe.Pointer(e.pic.img.plane[1]), unsafe.Pointer(&frame[planeSize]), C.size_t(chromaSize))
+	} else {
+		// Fully planar I420: separate U and V planes, a quarter of the
+		// luma size each.
+		chromaSize := planeSize / 4
+		if len(frame) < planeSize+2*chromaSize {
+			return nil, fmt.Errorf("x264 encoder: frame too small for I420 %dx%d", e.cfg.Width, e.cfg.Height)
+		}
+		C.memcpy(unsafe.Pointer(e.pic.img.plane[0]), unsafe.Pointer(&frame[0]), C.size_t(planeSize))
+		C.memcpy(unsafe.Pointer(e.pic.img.plane[1]), unsafe.Pointer(&frame[planeSize]), C.size_t(chromaSize))
+		C.memcpy(unsafe.Pointer(e.pic.img.plane[2]), unsafe.Pointer(&frame[planeSize+chromaSize]), C.size_t(chromaSize))
+	}
+	e.pic.i_pts = C.int64_t(pts)
+
+	var nals *C.x264_nal_t
+	var nalCount C.int
+	var outPic C.x264_picture_t
+
+	size := C.x264_encoder_encode(e.enc, &nals, &nalCount, &e.pic, &outPic)
+	if size < 0 {
+		return nil, fmt.Errorf("x264 encoder: encode failed")
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	data := C.GoBytes(unsafe.Pointer(nals.p_payload), size)
+	return []Packet{{
+		Data:     data,
+		PTS:      int64(outPic.i_pts),
+		Keyframe: outPic.b_keyframe != 0,
+	}}, nil
+}
+
+func (e *x264Encoder) Close() error {
+	if e.enc != nil {
+		C.x264_picture_clean(&e.pic)
+		C.x264_encoder_close(e.enc)
+		e.enc = nil
+	}
+	return nil
+}
@@ -1,3 +1,6 @@
+//go:build !native && !cpu
+// +build !native,!cpu
+
 package main
 
 import (
@@ -5,12 +8,18 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/backend"
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/broadcast"
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/clip"
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/control"
 )
 
 type streamerConfig struct {
@@ -50,31 +59,299 @@ type streamerConfig struct {
 	sineFrequency     string
 	generateSineAudio bool
 	useTestPattern    bool
+	broadcastURLs     []string
+	encoderBackend    string
+	clipBufferSeconds int
+
+	// keyframeZmqAddr, when non-empty, is a bind address (e.g.
+	// "tcp://127.0.0.1:5555") for a `zmq` filter added to the ffmpeg
+	// video filter chain, letting POST /keyframe reach the running
+	// pipeline without a restart. keyframeZmqTarget/keyframeZmqCommand
+	// name the filtergraph node and command to send; zmqsendBinary is
+	// the zmqsend CLI used to send it. Empty keyframeZmqAddr disables
+	// the feature and POST /keyframe responds 501.
+	keyframeZmqAddr    string
+	keyframeZmqTarget  string
+	keyframeZmqCommand string
+	zmqsendBinary      string
+}
+
+// newBackend builds the backend.Backend selected by cfg.encoderBackend.
+// clipPipe, if non-nil, is the write end of a pipe the ffmpeg backend
+// should mux a second `-c copy -f mpegts` copy of the stream into at
+// fd 3, for the clip buffer to demux; it is ignored by the gstreamer
+// backend, which has no equivalent tee today.
+func newBackend(cfg streamerConfig, clipPipe *os.File) (backend.Backend, error) {
+	switch cfg.encoderBackend {
+	case "", "ffmpeg":
+		args := buildFFmpegArgs(cfg)
+		fb := backend.NewFFmpegBackend(cfg.ffmpegBinary, args)
+		if clipPipe != nil {
+			fb.Args = append(fb.Args, "-c", "copy", "-f", "mpegts", "pipe:3")
+			fb.ExtraFiles = []*os.File{clipPipe}
+		}
+		fb.ZmqCommandAddr = cfg.keyframeZmqAddr
+		fb.ZmqCommandTarget = cfg.keyframeZmqTarget
+		fb.ZmqCommandText = cfg.keyframeZmqCommand
+		fb.ZmqsendBinary = cfg.zmqsendBinary
+		return fb, nil
+	case "gstreamer":
+		bitrateKbps, err := bitrateKbps(cfg.videoBitrate)
+		if err != nil {
+			return nil, fmt.Errorf("gstreamer backend: %w", err)
+		}
+		return backend.NewGStreamerBackend(backend.GstConfig{
+			CameraDevice:  cfg.cameraDevice,
+			Width:         cfg.resolutionWidth(),
+			Height:        cfg.resolutionHeight(),
+			FrameRate:     cfg.frameRateInt,
+			BitrateKbps:   bitrateKbps,
+			GOPSize:       cfg.gopSize,
+			StreamName:    cfg.streamName,
+			TargetHost:    cfg.targetHost,
+			PublishUser:   cfg.publishUser,
+			PublishPass:   cfg.publishPass,
+			RTSPTransport: cfg.rtspTransport,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown ENCODER_BACKEND %q (want ffmpeg or gstreamer)", cfg.encoderBackend)
+	}
+}
+
+// keyframeCapable is implemented by backends that can force an
+// out-of-band keyframe on their live pipeline without a restart.
+type keyframeCapable interface {
+	SendKeyframeCommand(ctx context.Context) error
+}
+
+// pipelineHandle lets the control API reach whichever backend is
+// currently running, since a new backend.Backend is built on every
+// reconfigure/retry loop iteration in main.
+type pipelineHandle struct {
+	mu      sync.Mutex
+	current keyframeCapable
+}
+
+// set records b as the active backend, if it supports forcing a
+// keyframe; otherwise sendKeyframe will report it unavailable.
+func (h *pipelineHandle) set(b backend.Backend) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current, _ = b.(keyframeCapable)
+}
+
+func (h *pipelineHandle) sendKeyframe(ctx context.Context) error {
+	h.mu.Lock()
+	cur := h.current
+	h.mu.Unlock()
+	if cur == nil {
+		return fmt.Errorf("active backend cannot force a keyframe without a restart")
+	}
+	return cur.SendKeyframeCommand(ctx)
+}
+
+// bitrateKbps parses a VIDEO_BITRATE-style value ("2M", "512k",
+// "800000") into kbps for the GStreamer pipeline. ffmpeg's -b:v takes
+// the same string and treats a bare number as bits per second, so a
+// bare number here is converted down from bps to kbps to match.
+func bitrateKbps(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasSuffix(strings.ToUpper(value), "M"):
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid bitrate %q", value)
+		}
+		return n * 1000, nil
+	case strings.HasSuffix(strings.ToUpper(value), "K"):
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid bitrate %q", value)
+		}
+		return n, nil
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bitrate %q", value)
+		}
+		return n / 1000, nil
+	}
+}
+
+func (cfg streamerConfig) resolutionWidth() int {
+	w, _ := parseResolutionDims(cfg.resolution)
+	return w
+}
+
+func (cfg streamerConfig) resolutionHeight() int {
+	_, h := parseResolutionDims(cfg.resolution)
+	return h
+}
+
+func parseResolutionDims(res string) (int, int) {
+	var w, h int
+	if _, err := fmt.Sscanf(res, "%dx%d", &w, &h); err != nil {
+		log.Printf("invalid VIDEO_SIZE %q, using 1280x720: %v", res, err)
+		return 1280, 720
+	}
+	return w, h
 }
 
 func main() {
-	cfg := loadConfig()
+	initial := loadConfig()
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
 	logger := log.New(os.Stdout, "streamer: ", log.LstdFlags|log.Lmicroseconds)
-	logger.Printf("starting streamer with camera=%s target=%s stream=%s", cfg.cameraDevice, cfg.targetHost, cfg.streamName)
-	if cfg.publishUser == "" {
+	logger.Printf("starting streamer with camera=%s target=%s stream=%s", initial.cameraDevice, initial.targetHost, initial.streamName)
+	if initial.publishUser == "" {
 		logger.Println("warning: RELAY_PUBLISH_USER is empty; publishing will fail if the relay requires authentication")
 	}
-	if cfg.publishUser != "" && cfg.publishPass == "" {
+	if initial.publishUser != "" && initial.publishPass == "" {
 		logger.Println("warning: RELAY_PUBLISH_PASS is empty while RELAY_PUBLISH_USER is set")
 	}
 
+	if readEnvBool("ENABLE_ADAPTIVE_BITRATE", false) {
+		// The ffmpeg-exec pipeline publishes over a TCP-interleaved RTSP
+		// connection that ffmpeg itself owns, so there is no RTCP
+		// receiver report for this process to read. The adaptive bitrate
+		// controller is wired up for the native publisher (build tag
+		// `native`), which terminates the RTSP session itself.
+		logger.Println("warning: ENABLE_ADAPTIVE_BITRATE has no effect on the ffmpeg-exec pipeline; build with -tags native to get RTCP-driven bitrate control")
+	}
+
+	// The manager outlives this setup block (ctx-scoped) so the control
+	// API can toggle sinks at runtime, not just at startup.
+	broadcastLogger := log.New(os.Stdout, "broadcast: ", log.LstdFlags|log.Lmicroseconds)
+	broadcastMgr := broadcast.NewManager(ctx, initial.ffmpegBinary, buildRTSPURL(initial), broadcastLogger)
+	for _, url := range initial.broadcastURLs {
+		if err := broadcastMgr.Start(url); err != nil {
+			broadcastLogger.Printf("failed to start sink %s: %v", url, err)
+		}
+	}
+
+	var cfgPtr atomic.Pointer[streamerConfig]
+	cfgPtr.Store(&initial)
+	reconfigure := make(chan struct{}, 1)
+
+	var clipQueue *clip.Queue
+	if initial.clipBufferSeconds > 0 {
+		if initial.encoderBackend != "" && initial.encoderBackend != "ffmpeg" {
+			logger.Printf("warning: CLIP_BUFFER_SECONDS has no effect on the %s backend; the clip buffer only taps the ffmpeg-exec pipeline's second output", initial.encoderBackend)
+		} else {
+			clipQueue = clip.NewQueue(time.Duration(initial.clipBufferSeconds) * time.Second)
+		}
+	}
+
+	var exportClip func(ctx context.Context, seconds int) ([]byte, error)
+	if clipQueue != nil {
+		exportClip = func(ctx context.Context, seconds int) ([]byte, error) {
+			now := time.Now()
+			packets := clipQueue.Slice(now.Add(-time.Duration(seconds)*time.Second), now)
+			return clip.ExportMP4(ctx, initial.ffmpegBinary, packets)
+		}
+	}
+
+	var pipeline pipelineHandle
+	var sendKeyframe func(ctx context.Context) error
+	if (initial.encoderBackend == "" || initial.encoderBackend == "ffmpeg") && initial.keyframeZmqAddr != "" {
+		sendKeyframe = pipeline.sendKeyframe
+	}
+
+	controlAddr := readEnv("CONTROL_ADDR", ":8081")
+	controlLogger := log.New(os.Stdout, "control: ", log.LstdFlags|log.Lmicroseconds)
+	server := control.NewServer(controlLogger,
+		func() any { return controlStatus(cfgPtr.Load(), broadcastMgr) },
+		func(patch control.ConfigPatch) error { return applyConfigPatch(&cfgPtr, patch, reconfigure) },
+		func() { signalReconfigure(reconfigure) },
+		exportClip,
+		sendKeyframe,
+		func(url string) error { return broadcastMgr.Start(url) },
+		func(url string) error { return broadcastMgr.Stop(url) },
+		func() []control.BroadcastSinkStatus { return broadcastSinkStatuses(broadcastMgr) },
+	)
+	go func() {
+		if err := server.Start(ctx, controlAddr); err != nil {
+			controlLogger.Printf("control server stopped: %v", err)
+		}
+	}()
+
 	retryDelay := 3 * time.Second
 
 	for {
-		err := runFFmpeg(ctx, cfg, logger)
+		cfg := *cfgPtr.Load()
+
+		var clipPipeW *os.File
+		if clipQueue != nil {
+			pr, pw, err := os.Pipe()
+			if err != nil {
+				logger.Printf("clip buffer: failed to create pipe, disabling for this run: %v", err)
+			} else {
+				clipPipeW = pw
+				go func() {
+					if err := clip.ReadTS(ctx, pr, clipQueue); err != nil {
+						logger.Printf("clip buffer: demux stopped: %v", err)
+					}
+					pr.Close()
+				}()
+			}
+		}
+
+		b, err := newBackend(cfg, clipPipeW)
+		if err != nil {
+			logger.Printf("failed to select backend: %v", err)
+			if clipPipeW != nil {
+				clipPipeW.Close()
+			}
+			select {
+			case <-time.After(retryDelay):
+				continue
+			case <-ctx.Done():
+				logger.Println("shutdown requested during backoff, exiting")
+				return
+			}
+		}
+		pipeline.set(b)
+
+		if err := b.Build(); err != nil {
+			logger.Printf("failed to build %s backend: %v", b.Name(), err)
+			if clipPipeW != nil {
+				clipPipeW.Close()
+			}
+			select {
+			case <-time.After(retryDelay):
+				continue
+			case <-ctx.Done():
+				logger.Println("shutdown requested during backoff, exiting")
+				return
+			}
+		}
+
+		runCtx, cancelRun := context.WithCancel(ctx)
+		var reconfigured atomic.Bool
+		go func() {
+			select {
+			case <-reconfigure:
+				reconfigured.Store(true)
+				cancelRun()
+			case <-runCtx.Done():
+			}
+		}()
+
+		logger.Printf("launching %s backend", b.Name())
+		runErr := b.Run(runCtx)
+		cancelRun()
+		pipeline.set(nil)
+
 		if ctx.Err() != nil {
 			logger.Println("shutdown requested, exiting")
 			return
 		}
-		logger.Printf("ffmpeg exited: %v", err)
+		if reconfigured.Load() {
+			logger.Println("reconfiguring pipeline")
+			continue
+		}
+		logger.Printf("%s backend exited: %v", b.Name(), runErr)
 		logger.Printf("retrying in %s", retryDelay)
 		select {
 		case <-time.After(retryDelay):
@@ -85,6 +362,75 @@ func main() {
 	}
 }
 
+// controlStatus is the JSON body served from GET /status.
+func controlStatus(cfg *streamerConfig, broadcastMgr *broadcast.Manager) map[string]any {
+	return map[string]any{
+		"videoBitrate":      cfg.videoBitrate,
+		"videoMaxRate":      cfg.videoMaxRate,
+		"frameRate":         cfg.frameRate,
+		"videoRotation":     cfg.videoRotation,
+		"gopSize":           cfg.gopSize,
+		"refreshMode":       cfg.refreshMode,
+		"clipBufferSeconds": cfg.clipBufferSeconds,
+		"broadcastSinks":    broadcastSinkStatuses(broadcastMgr),
+	}
+}
+
+// broadcastSinkStatuses adapts broadcast.Manager's status snapshot to
+// the control package's wire type, keeping control independent of the
+// broadcast package's internal sink bookkeeping.
+func broadcastSinkStatuses(broadcastMgr *broadcast.Manager) []control.BroadcastSinkStatus {
+	sinks := broadcastMgr.Status()
+	out := make([]control.BroadcastSinkStatus, len(sinks))
+	for i, s := range sinks {
+		out[i] = control.BroadcastSinkStatus{
+			URL:      s.URL,
+			Running:  s.Running,
+			Restarts: s.Restarts,
+			LastErr:  s.LastErr,
+		}
+	}
+	return out
+}
+
+// applyConfigPatch merges a ConfigPatch onto the live config and
+// wakes up the pipeline loop to relaunch ffmpeg with it.
+func applyConfigPatch(cfgPtr *atomic.Pointer[streamerConfig], patch control.ConfigPatch, reconfigure chan<- struct{}) error {
+	current := *cfgPtr.Load()
+	if patch.VideoBitrate != nil {
+		current.videoBitrate = *patch.VideoBitrate
+	}
+	if patch.VideoMaxRate != nil {
+		current.videoMaxRate = *patch.VideoMaxRate
+	}
+	if patch.FrameRate != nil {
+		current.frameRate = *patch.FrameRate
+		current.frameRateInt = parsePositiveInt(*patch.FrameRate, current.frameRateInt)
+	}
+	if patch.VideoRotation != nil {
+		current.videoRotation = parseRotation(strconv.Itoa(*patch.VideoRotation))
+	}
+	if patch.GopSize != nil {
+		current.gopSize = *patch.GopSize
+	}
+	if patch.RefreshMode != nil {
+		current.refreshMode = strings.ToLower(*patch.RefreshMode)
+	}
+
+	cfgPtr.Store(&current)
+	signalReconfigure(reconfigure)
+	return nil
+}
+
+// signalReconfigure wakes the pipeline loop without blocking if a
+// reconfigure is already pending.
+func signalReconfigure(reconfigure chan<- struct{}) {
+	select {
+	case reconfigure <- struct{}{}:
+	default:
+	}
+}
+
 func loadConfig() streamerConfig {
 	baseBitrate := readEnv("VIDEO_BITRATE", "2M")
 	frameRate := readEnv("FRAME_RATE", "30")
@@ -134,22 +480,31 @@ func loadConfig() streamerConfig {
 		sineFrequency:     readEnv("SINE_FREQUENCY", "1000"),
 		generateSineAudio: readEnvBool("GENERATE_SINE_AUDIO", true),
 		useTestPattern:    readEnvBool("USE_TEST_PATTERN", false),
+		broadcastURLs:     parseBroadcastURLs(os.Getenv("BROADCAST_URLS")),
+		encoderBackend:    strings.ToLower(readEnv("ENCODER_BACKEND", "ffmpeg")),
+		clipBufferSeconds: parseNonNegativeInt(os.Getenv("CLIP_BUFFER_SECONDS"), 0),
+
+		keyframeZmqAddr:    os.Getenv("KEYFRAME_ZMQ_ADDR"),
+		keyframeZmqTarget:  readEnv("KEYFRAME_ZMQ_TARGET", "out"),
+		keyframeZmqCommand: readEnv("KEYFRAME_ZMQ_COMMAND", "force_key_frame 1"),
+		zmqsendBinary:      readEnv("ZMQSEND_BINARY", "zmqsend"),
 	}
 }
 
-func runFFmpeg(ctx context.Context, cfg streamerConfig, logger *log.Logger) error {
-	args := buildFFmpegArgs(cfg)
-	logger.Printf("launching ffmpeg (%d args)", len(args))
-
-	cmd := exec.CommandContext(ctx, cfg.ffmpegBinary, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start ffmpeg: %w", err)
+// parseBroadcastURLs splits the comma-separated BROADCAST_URLS env var
+// into individual sink URLs, dropping empty entries.
+func parseBroadcastURLs(value string) []string {
+	if value == "" {
+		return nil
 	}
-
-	return cmd.Wait()
+	var urls []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			urls = append(urls, part)
+		}
+	}
+	return urls
 }
 
 func buildFFmpegArgs(cfg streamerConfig) []string {
@@ -315,7 +670,18 @@ func buildVideoFilters(cfg streamerConfig) []string {
 	case 270:
 		filters = append(filters, "transpose=2")
 	}
-	filters = append(filters, fmt.Sprintf("format=%s", cfg.videoFormat))
+	// Named so it can be addressed as the default KEYFRAME_ZMQ_TARGET
+	// below -- force_key_frame is only as real as whatever filter the
+	// operator points the command at, but the chain needs at least one
+	// named node for "out" (the default target) to resolve to anything.
+	filters = append(filters, fmt.Sprintf("format@out=%s", cfg.videoFormat))
+	if cfg.keyframeZmqAddr != "" {
+		// ffmpeg's filter option parser splits on unescaped ':', so the
+		// ':' in "tcp://host:port" has to be escaped or bind_address
+		// gets truncated at the scheme and the filtergraph fails to parse.
+		escapedAddr := strings.ReplaceAll(cfg.keyframeZmqAddr, ":", "\\:")
+		filters = append(filters, fmt.Sprintf("zmq=bind_address=%s", escapedAddr))
+	}
 	return filters
 }
 
@@ -0,0 +1,241 @@
+//go:build native
+// +build native
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/abr"
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/capture"
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/encoder"
+	"github.com/02loveslollipop/LoRa-L298N-TankController/sbc_streamer/publisher"
+)
+
+// errNativeReconfigure signals that runNative stopped to pick up a new
+// target bitrate from the adaptive bitrate controller, not because the
+// pipeline failed, so main's retry loop should skip its backoff.
+var errNativeReconfigure = errors.New("native: reconfigure requested")
+
+type nativeStreamerConfig struct {
+	cameraDevice  string
+	resolution    string
+	width         int
+	height        int
+	pixelFormat   string
+	minBitrateBps int
+	maxBitrateBps int
+	gopSize       int
+	streamName    string
+	targetHost    string
+	publishUser   string
+	publishPass   string
+	rtspTransport string
+	frameRateInt  int
+
+	// bitrateMu guards bitrate, which the adaptive bitrate controller's
+	// OnReceiverReport callback writes from gortsplib's RTCP read
+	// goroutine while runNative/main read it from the main goroutine.
+	bitrateMu sync.Mutex
+	bitrate   int
+}
+
+func (c *nativeStreamerConfig) getBitrate() int {
+	c.bitrateMu.Lock()
+	defer c.bitrateMu.Unlock()
+	return c.bitrate
+}
+
+func (c *nativeStreamerConfig) setBitrate(v int) {
+	c.bitrateMu.Lock()
+	c.bitrate = v
+	c.bitrateMu.Unlock()
+}
+
+func main() {
+	cfg := loadNativeConfig()
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	logger := log.New(os.Stdout, "native-streamer: ", log.LstdFlags|log.Lmicroseconds)
+	logger.Printf("starting native streamer with camera=%s target=%s stream=%s", cfg.cameraDevice, cfg.targetHost, cfg.streamName)
+
+	retryDelay := 3 * time.Second
+	for {
+		err := runNative(ctx, cfg, logger)
+		if ctx.Err() != nil {
+			logger.Println("shutdown requested, exiting")
+			return
+		}
+		if errors.Is(err, errNativeReconfigure) {
+			logger.Printf("restarting pipeline with bitrate=%d", cfg.getBitrate())
+			continue
+		}
+		logger.Printf("native pipeline exited: %v", err)
+		logger.Printf("retrying in %s", retryDelay)
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			logger.Println("shutdown requested during backoff, exiting")
+			return
+		}
+	}
+}
+
+func loadNativeConfig() *nativeStreamerConfig {
+	resolution := readEnv("VIDEO_SIZE", "1280x720")
+	width, height := parseResolution(resolution)
+	frameRateInt := parsePositiveInt(os.Getenv("FRAME_RATE"), 30)
+	bitrate := parsePositiveInt(os.Getenv("VIDEO_BITRATE_BPS"), 2_000_000)
+	return &nativeStreamerConfig{
+		cameraDevice:  readEnv("CAMERA_DEVICE", "/dev/video0"),
+		resolution:    resolution,
+		width:         width,
+		height:        height,
+		pixelFormat:   readEnv("VIDEO_FORMAT", "nv12"),
+		frameRateInt:  frameRateInt,
+		bitrate:       bitrate,
+		minBitrateBps: parsePositiveInt(os.Getenv("MIN_BITRATE_BPS"), bitrate/2),
+		maxBitrateBps: parsePositiveInt(os.Getenv("MAX_BITRATE_BPS"), bitrate*2),
+		gopSize:       parsePositiveInt(os.Getenv("GOP_SIZE"), frameRateInt),
+		streamName:    readEnv("STREAM_NAME", "robot"),
+		targetHost:    readEnv("RELAY_HOST", "rtsp.nene.02labs.me:8554"),
+		publishUser:   readEnv("RELAY_PUBLISH_USER", ""),
+		publishPass:   readEnv("RELAY_PUBLISH_PASS", ""),
+		rtspTransport: readEnv("RTSP_TRANSPORT", "tcp"),
+	}
+}
+
+// runNative wires V4L2 capture through the in-process encoder into
+// the native RTSP publisher, and blocks until the pipeline fails, ctx
+// is cancelled, or the adaptive bitrate controller asks for a
+// restart with a new target bitrate (in which case cfg's bitrate is
+// updated, via setBitrate, before returning errNativeReconfigure).
+func runNative(ctx context.Context, cfg *nativeStreamerConfig, logger *log.Logger) error {
+	dev, err := capture.Open(cfg.cameraDevice, cfg.width, cfg.height, cfg.pixelFormat)
+	if err != nil {
+		return err
+	}
+	defer dev.Close()
+
+	if err := dev.Start(ctx); err != nil {
+		return err
+	}
+
+	enc, err := encoder.New(encoder.Config{
+		Width:     cfg.width,
+		Height:    cfg.height,
+		PixelFmt:  cfg.pixelFormat,
+		Bitrate:   cfg.getBitrate(),
+		GOPSize:   cfg.gopSize,
+		FrameRate: cfg.frameRateInt,
+	})
+	if err != nil {
+		return err
+	}
+	defer enc.Close()
+
+	pub := publisher.NewNativePublisher(publisher.Config{
+		TargetHost:    cfg.targetHost,
+		StreamName:    cfg.streamName,
+		PublishUser:   cfg.publishUser,
+		PublishPass:   cfg.publishPass,
+		RTSPTransport: cfg.rtspTransport,
+	})
+
+	reconfigure := make(chan struct{}, 1)
+	controller := abr.NewController(abr.DefaultConfig(cfg.minBitrateBps, cfg.maxBitrateBps), cfg.getBitrate())
+	pub.OnReceiverReport(func(r publisher.RTCPReport) {
+		newBitrate, changed, needsRestart := controller.Update(abr.Report{
+			FractionLost: r.FractionLost,
+			JitterMillis: r.JitterMillis,
+			RTTMillis:    r.RTTMillis,
+		})
+		if !changed {
+			return
+		}
+		if !needsRestart {
+			logger.Printf("adaptive bitrate: target now %d bps, but in-place updates need a live-reconfigurable encoder; applying on next restart", newBitrate)
+			cfg.setBitrate(newBitrate)
+			return
+		}
+		logger.Printf("adaptive bitrate: target changed by more than 15%%, restarting encoder at %d bps", newBitrate)
+		cfg.setBitrate(newBitrate)
+		select {
+		case reconfigure <- struct{}{}:
+		default:
+		}
+	})
+
+	if err := pub.Connect(ctx); err != nil {
+		return err
+	}
+	defer pub.Close()
+
+	frameDuration := time.Second / time.Duration(cfg.frameRateInt)
+	pts := int64(0)
+	frames := dev.Frames()
+	for {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				return nil
+			}
+			packets, err := enc.Encode(frame, pts)
+			if err != nil {
+				return err
+			}
+			for _, pkt := range packets {
+				if err := pub.WriteSample(publisher.Sample{
+					Data:     pkt.Data,
+					PTS:      time.Duration(pkt.PTS),
+					Keyframe: pkt.Keyframe,
+				}); err != nil {
+					return err
+				}
+			}
+			pts += int64(frameDuration)
+		case <-reconfigure:
+			return errNativeReconfigure
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func parseResolution(res string) (int, int) {
+	var w, h int
+	if _, err := fmt.Sscanf(res, "%dx%d", &w, &h); err != nil {
+		log.Printf("invalid VIDEO_SIZE %q, using 1280x720: %v", res, err)
+		return 1280, 720
+	}
+	return w, h
+}
+
+func readEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func parsePositiveInt(value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v <= 0 {
+		log.Printf("invalid positive int %q, using fallback %d", value, fallback)
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync/atomic"
+)
+
+// FFmpegPublisher publishes raw H.264 access units to an RTSP relay by
+// piping them into an `ffmpeg -f h264 -c:v copy -f rtsp ...` child
+// process over stdin. It is the thin wrapper alongside which
+// NativePublisher (build tag `native`) was added: same interface, no
+// in-process RTSP stack.
+type FFmpegPublisher struct {
+	binary string
+	cfg    Config
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	frames atomic.Uint64
+	bytes  atomic.Uint64
+}
+
+// NewFFmpegPublisher returns a Publisher that shells out to ffmpeg for
+// the actual RTSP session.
+func NewFFmpegPublisher(binary string, cfg Config) *FFmpegPublisher {
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	return &FFmpegPublisher{binary: binary, cfg: cfg}
+}
+
+func (p *FFmpegPublisher) Connect(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, p.binary,
+		"-f", "h264", "-i", "pipe:0",
+		"-c:v", "copy",
+		"-rtsp_transport", p.cfg.RTSPTransport,
+		"-f", "rtsp", p.cfg.URL(),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("ffmpeg publisher: stdin pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ffmpeg publisher: start: %w", err)
+	}
+
+	p.cmd = cmd
+	p.stdin = stdin
+	return nil
+}
+
+func (p *FFmpegPublisher) WriteSample(s Sample) error {
+	if p.stdin == nil {
+		return fmt.Errorf("ffmpeg publisher: not connected")
+	}
+	n, err := p.stdin.Write(s.Data)
+	if err != nil {
+		return fmt.Errorf("ffmpeg publisher: write sample: %w", err)
+	}
+	p.frames.Add(1)
+	p.bytes.Add(uint64(n))
+	return nil
+}
+
+func (p *FFmpegPublisher) Close() error {
+	if p.stdin != nil {
+		p.stdin.Close()
+		p.stdin = nil
+	}
+	if p.cmd == nil {
+		return nil
+	}
+	err := p.cmd.Wait()
+	p.cmd = nil
+	return err
+}
+
+func (p *FFmpegPublisher) Stats() Stats {
+	return Stats{
+		FramesSent: p.frames.Load(),
+		BytesSent:  p.bytes.Load(),
+	}
+}
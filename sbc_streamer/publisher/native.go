@@ -0,0 +1,204 @@
+//go:build native
+// +build native
+
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/format/rtph264"
+	"github.com/bluenviron/mediacommon/pkg/codecs/h264"
+	"github.com/pion/rtcp"
+)
+
+// RTCPReport is the subset of an RTCP receiver report the adaptive
+// bitrate controller cares about.
+type RTCPReport struct {
+	FractionLost float64 // 0..1
+	JitterMillis float64
+	RTTMillis    float64 // 0 if no RTT estimate is available yet
+}
+
+// h264ClockRate is the RTP clock rate for H.264, used to convert an
+// RTCP receiver report's jitter (in RTP timestamp units) to
+// milliseconds.
+const h264ClockRate = 90_000
+
+// ntpEpochOffset is the number of seconds between the NTP epoch
+// (1900-01-01) and the Unix epoch, needed to express time.Now() in
+// the same 32-bit NTP "middle 32 bits" format as a receiver report's
+// LastSenderReport field.
+const ntpEpochOffset = 2208988800
+
+// ntpMiddle32 converts t to the middle 32 bits of its 64-bit NTP
+// timestamp, matching the format of rtcp.ReceptionReport's
+// LastSenderReport and the "arrival" term in RFC 3550 A.8.
+func ntpMiddle32(t time.Time) uint32 {
+	sec := uint64(t.Unix()+ntpEpochOffset) << 32
+	frac := uint64(t.Nanosecond()) << 32 / 1e9
+	return uint32((sec | frac) >> 16)
+}
+
+// rttFromReceiverReport estimates the round-trip time to the relay
+// from a reception report block, per RFC 3550 A.8: RTT = arrival -
+// LSR - DLSR, all expressed in units of 1/65536s. LSR is zero until
+// the relay has seen one of our sender reports, in which case no
+// estimate is available yet.
+func rttFromReceiverReport(block rtcp.ReceptionReport, now time.Time) (millis float64, ok bool) {
+	if block.LastSenderReport == 0 {
+		return 0, false
+	}
+	// The subtraction is done mod 2^32 like the rest of this arithmetic,
+	// but a genuine RTT never approaches the ~9.1h range half of that
+	// wraps to -- so a result reinterpreted as negative is clock-skew/
+	// rounding noise, not a real measurement.
+	raw := int32(ntpMiddle32(now) - block.LastSenderReport - block.Delay)
+	if raw < 0 {
+		return 0, false
+	}
+	return float64(raw) / 65536 * 1000, true
+}
+
+// NativePublisher publishes H.264 access units directly over RTSP
+// using gortsplib, without spawning ffmpeg. It DESCRIBEs/ANNOUNCEs the
+// session, forces TCP interleaved transport (the relay sits behind
+// LoRa-link NAT, so UDP is not an option), and authenticates with
+// RELAY_PUBLISH_USER/PASS the same way the ffmpeg path does.
+type NativePublisher struct {
+	cfg   Config
+	track *format.H264
+
+	client *gortsplib.Client
+	media  *description.Media
+	rtpEnc *rtph264.Encoder
+	onRR   func(RTCPReport)
+
+	frames   atomic.Uint64
+	bytes    atomic.Uint64
+	rttNanos atomic.Int64 // last RTCP-derived RTT estimate, 0 if none yet
+}
+
+// OnReceiverReport registers a callback invoked for every RTCP
+// receiver report the relay sends back on the publish session. It
+// must be called before Connect. Used by the adaptive bitrate
+// controller to react to loss/jitter without a second connection.
+func (p *NativePublisher) OnReceiverReport(fn func(RTCPReport)) {
+	p.onRR = fn
+}
+
+// NewNativePublisher returns a Publisher backed by an in-process RTSP
+// client and H.264 RTP packetizer, ready to Connect.
+func NewNativePublisher(cfg Config) *NativePublisher {
+	track := &format.H264{
+		PayloadTyp:        96,
+		PacketizationMode: 1,
+	}
+	return &NativePublisher{
+		cfg:   cfg,
+		track: track,
+		media: &description.Media{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{track},
+		},
+	}
+}
+
+func (p *NativePublisher) Connect(ctx context.Context) error {
+	u, err := base.ParseURL(p.cfg.URL())
+	if err != nil {
+		return fmt.Errorf("native publisher: parse url: %w", err)
+	}
+
+	transport := gortsplib.TransportTCP
+	p.client = &gortsplib.Client{Transport: &transport}
+
+	if err := p.client.StartRecording(u.String(), &description.Session{Medias: []*description.Media{p.media}}); err != nil {
+		return fmt.Errorf("native publisher: announce/record: %w", err)
+	}
+
+	if p.onRR != nil {
+		p.client.OnPacketRTCPAny(func(_ *description.Media, pkt rtcp.Packet) {
+			rr, ok := pkt.(*rtcp.ReceiverReport)
+			if !ok || len(rr.Reports) == 0 {
+				return
+			}
+			block := rr.Reports[0]
+			report := RTCPReport{
+				FractionLost: float64(block.FractionLost) / 256,
+				JitterMillis: float64(block.Jitter) / h264ClockRate * 1000,
+			}
+			if rttMillis, ok := rttFromReceiverReport(block, time.Now()); ok {
+				report.RTTMillis = rttMillis
+				p.rttNanos.Store(int64(rttMillis * float64(time.Millisecond)))
+			}
+			p.onRR(report)
+		})
+	}
+
+	enc, err := p.track.CreateEncoder()
+	if err != nil {
+		p.client.Close()
+		p.client = nil
+		return fmt.Errorf("native publisher: create rtp encoder: %w", err)
+	}
+	p.rtpEnc = enc
+
+	go func() {
+		<-ctx.Done()
+		p.Close()
+	}()
+	return nil
+}
+
+func (p *NativePublisher) WriteSample(s Sample) error {
+	if p.client == nil || p.rtpEnc == nil {
+		return fmt.Errorf("native publisher: not connected")
+	}
+
+	// x264 is configured with b_annexb=1, so s.Data is a start-code
+	// delimited bytestream that can concatenate SPS+PPS+IDR into one
+	// access unit. rtph264.Encoder.Encode wants the individual NALUs,
+	// not the Annex-B blob -- split it first or the start codes end up
+	// in the RTP payload and the relay can't depacketize it.
+	var au h264.AnnexB
+	if err := au.Unmarshal(s.Data); err != nil {
+		return fmt.Errorf("native publisher: split access unit: %w", err)
+	}
+
+	pkts, err := p.rtpEnc.Encode(au)
+	if err != nil {
+		return fmt.Errorf("native publisher: packetize sample: %w", err)
+	}
+	for _, pkt := range pkts {
+		if err := p.client.WritePacketRTP(p.media, pkt); err != nil {
+			return fmt.Errorf("native publisher: write rtp packet: %w", err)
+		}
+	}
+	p.frames.Add(1)
+	p.bytes.Add(uint64(len(s.Data)))
+	return nil
+}
+
+func (p *NativePublisher) Close() error {
+	if p.client == nil {
+		return nil
+	}
+	p.client.Close()
+	p.client = nil
+	return nil
+}
+
+func (p *NativePublisher) Stats() Stats {
+	return Stats{
+		FramesSent:    p.frames.Load(),
+		BytesSent:     p.bytes.Load(),
+		LastRTTMillis: float64(p.rttNanos.Load()) / float64(time.Millisecond),
+	}
+}
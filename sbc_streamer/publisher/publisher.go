@@ -0,0 +1,61 @@
+// Package publisher defines the interface used to push encoded video
+// samples to the relay over RTSP, independent of how the bytes are
+// produced (ffmpeg subprocess today, in-process encoder with the
+// `native` build tag).
+package publisher
+
+import (
+	"context"
+	"time"
+)
+
+// Sample is a single encoded access unit ready to be written to the
+// RTSP session.
+type Sample struct {
+	Data     []byte
+	PTS      time.Duration
+	Keyframe bool
+}
+
+// Stats reports runtime counters for the active publish session, used
+// by the control API to surface link health to the operator.
+type Stats struct {
+	FramesSent    uint64
+	BytesSent     uint64
+	LastRTTMillis float64 // 0 if no RTCP-derived RTT estimate is available yet
+}
+
+// Publisher connects to an RTSP relay, sends encoded samples, and
+// reports runtime stats. Connect must be called before WriteSample;
+// Close releases the underlying session and is safe to call more than
+// once.
+type Publisher interface {
+	Connect(ctx context.Context) error
+	WriteSample(s Sample) error
+	Close() error
+	Stats() Stats
+}
+
+// Config carries the subset of streamerConfig a Publisher needs to
+// reach the relay, common to every implementation.
+type Config struct {
+	TargetHost    string
+	StreamName    string
+	PublishUser   string
+	PublishPass   string
+	RTSPTransport string
+}
+
+// URL builds the rtsp:// URL a Publisher should announce/publish to,
+// matching the scheme buildRTSPURL uses for the ffmpeg-exec path.
+func (c Config) URL() string {
+	var auth string
+	if c.PublishUser != "" {
+		auth = c.PublishUser
+		if c.PublishPass != "" {
+			auth += ":" + c.PublishPass
+		}
+		auth += "@"
+	}
+	return "rtsp://" + auth + c.TargetHost + "/" + c.StreamName
+}